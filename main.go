@@ -1,15 +1,25 @@
 package main
 
 import (
+	"bytes"
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
 	"flag"
 	"fmt"
-	"io"
 	"log"
+	"net"
+	"net/http"
+	"net/url"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"runtime"
 	"strconv"
 	"strings"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/common-nighthawk/go-figure"
@@ -20,6 +30,7 @@ import (
 	v1 "github.com/prometheus/client_golang/api/prometheus/v1"
 	"github.com/prometheus/common/model"
 
+	"github.com/fsnotify/fsnotify"
 	"github.com/go-co-op/gocron"
 )
 
@@ -30,6 +41,54 @@ type Datasource struct {
 	Unit  string  `json:"unit"`
 	Warn  float64 `json:"warn"`
 	Error float64 `json:"error"`
+	Type  string  `json:"type,omitempty"` // "range" (default), "instant" or "scalar"
+
+	// Auth and transport options for securing a per-source Prometheus endpoint (e.g. behind Grafana Cloud, Thanos, or an nginx auth proxy)
+	BasicAuthUser       string   `json:"basicAuthUser,omitempty"`
+	BasicAuthPass       string   `json:"basicAuthPass,omitempty"`
+	BearerToken         string   `json:"bearerToken,omitempty"`
+	BearerTokenFile     string   `json:"bearerTokenFile,omitempty"`
+	Headers             []string `json:"headers,omitempty"` // "Header-Name: value" pairs added to every request
+	CAFile              string   `json:"caFile,omitempty"`
+	CertFile            string   `json:"certFile,omitempty"`
+	KeyFile             string   `json:"keyFile,omitempty"`
+	InsecureSkipVerify  bool     `json:"insecureSkipVerify,omitempty"`
+	Timeout             int      `json:"timeout,omitempty"`             // Query timeout in seconds, defaults to 5
+	DialTimeout         int      `json:"dialTimeout,omitempty"`         // TCP dial timeout in seconds, defaults to 5
+	MaxIdleConnsPerHost int      `json:"maxIdleConnsPerHost,omitempty"` // Defaults to http.DefaultMaxIdleConnsPerHost
+
+	// Alerting: fires a webhook POST when this source crosses Warn/Error
+	Webhook        string `json:"webhook,omitempty"`        // Defaults to -webhook if unset
+	WebhookTimeout int    `json:"webhookTimeout,omitempty"` // Seconds, defaults to 5
+}
+
+// PanelConfig places one named panel ("clock", "date", "chart", "metric", "gauges", "log" or
+// "sysinfo") in a LayoutConfig row, sized as a fraction of that row's width.
+type PanelConfig struct {
+	Panel string  `json:"panel"`
+	Width float64 `json:"width"`
+}
+
+// RowConfig is a horizontal strip of the grid, sized as a fraction of the terminal's height.
+type RowConfig struct {
+	Height float64       `json:"height"`
+	Panels []PanelConfig `json:"panels"`
+}
+
+// LayoutConfig declares an optional ui.Grid layout in dashclock.json. When present it replaces the
+// built-in clock+chart layout, letting the same binary drive anything from a 40x16 terminal
+// (clock+chart only) to a 240x30 LCD (all panels) purely through config.
+type LayoutConfig struct {
+	Rows []RowConfig `json:"rows"`
+}
+
+// configFile is the new object-shaped dashclock.json. A bare JSON array is still accepted for
+// backwards compatibility and is treated as Datasources with no Layout.
+type configFile struct {
+	Datasources []Datasource  `json:"datasources"`
+	Layout      *LayoutConfig `json:"layout,omitempty"` // Grid widgets are built once at startup; not hot-reloadable
+	Timezone    string        `json:"timezone,omitempty"` // Overrides -timezone; reloadable via SIGHUP/file-watch
+	Refresh     int           `json:"refresh,omitempty"`  // Overrides -refresh; reloadable via SIGHUP/file-watch
 }
 
 var fontClock string
@@ -39,44 +98,96 @@ var fontDateWidth int // For right align
 var fontLabel string
 
 var dsConfig []Datasource
+var dsLayout *LayoutConfig // Optional grid layout from dashclock.json; nil means use the built-in clock+chart layout
 var datasourceCount = 0
+var uiMutex sync.RWMutex // Guards pClock/pDate/pMetric/chart0 and every ui.Render/ui.Clear/SetRect, since gocron jobs and the UI event loop both touch them
 var flagTz string
 var flagProm string
 var flagFile string
 var flagTest bool
 var flagRefresh int
+var flagWebhook string
+var flagWebhookRetry int
+var flagAlertResend int
+var flagCache string
 var timezone *time.Location
 var width int
 var height int
 
 const nullValue = -999 // Since float64 cannot be null, we just use a unique value
 
+// parseConfigFile reads and parses path, accepting both the legacy bare-array format (just the
+// datasources, no layout/timezone/refresh overrides) and the newer object format.
+func parseConfigFile(path string) (configFile, error) {
+	var cfg configFile
+	byteValue, err := os.ReadFile(path)
+	if err != nil {
+		return cfg, err
+	}
+	trimmed := bytes.TrimSpace(byteValue)
+	if len(trimmed) > 0 && trimmed[0] == '[' { // Legacy format: a bare array of datasources, no layout
+		err = json.Unmarshal(byteValue, &cfg.Datasources)
+		return cfg, err
+	}
+	err = json.Unmarshal(byteValue, &cfg)
+	return cfg, err
+}
+
+// validateConfig sanity-checks a freshly-parsed config before it's allowed to replace the live one
+// via hot-reload: it must have at least one datasource, each with a well-formed Prom URL and sane
+// Warn/Error thresholds, and a loadable Timezone override if one is set.
+func validateConfig(cfg configFile) error {
+	if len(cfg.Datasources) == 0 {
+		return fmt.Errorf("no datasources configured")
+	}
+	for _, ds := range cfg.Datasources {
+		u, err := url.Parse(ds.Prom)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return fmt.Errorf("datasource %q has an invalid prom URL %q", ds.Title, ds.Prom)
+		}
+		if ds.Warn > 0 && ds.Error > 0 && ds.Warn >= ds.Error {
+			return fmt.Errorf("datasource %q has warn (%v) >= error (%v)", ds.Title, ds.Warn, ds.Error)
+		}
+	}
+	if cfg.Timezone != "" {
+		if _, err := time.LoadLocation(cfg.Timezone); err != nil {
+			return fmt.Errorf("invalid timezone %q: %w", cfg.Timezone, err)
+		}
+	}
+	return nil
+}
+
 func loadConfig() {
 	flag.BoolVar(&flagTest, "test", false, "test")
 	flag.IntVar(&flagRefresh, "refresh", 15, "Refresh rate (for metrics)")
 	flag.StringVar(&flagTz, "timezone", "Asia/Singapore", "Timezone")
 	flag.StringVar(&flagFile, "file", "dashclock.json", "Prometheus sources in JSON format")
+	flag.StringVar(&flagWebhook, "webhook", "", "Default alert webhook URL (used when a datasource doesn't set its own)")
+	flag.IntVar(&flagWebhookRetry, "webhook-retry", 2, "Number of retries for a failed alert webhook POST")
+	flag.IntVar(&flagAlertResend, "alert-resend", 15, "Minutes between repeat alert webhooks while a metric stays in the same state")
+	flag.StringVar(&flagCache, "cache", "dashclock.cache.json", "Path to the on-disk cache of last-known metric samples")
 	flag.Parse()
 
-	if _, err := os.Stat(flagFile); err == nil { // If file exists
-		jsonFile, err := os.Open(flagFile)
-		if err != nil {
-			log.Fatal(err)
-		}
-		byteValue, _ := io.ReadAll(jsonFile)
-		err = json.Unmarshal(byteValue, &dsConfig)
-		if err != nil {
-			log.Fatal(err)
-		}
-	} else {
+	if _, err := os.Stat(flagFile); err != nil {
 		log.Fatalf("Missing json config %s", flagFile)
 	}
+	cfg, err := parseConfigFile(flagFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	dsConfig = cfg.Datasources
+	dsLayout = cfg.Layout
+	if cfg.Timezone != "" {
+		flagTz = cfg.Timezone
+	}
+	if cfg.Refresh > 0 {
+		flagRefresh = cfg.Refresh
+	}
 
 	datasourceCount = len(dsConfig)
 	if datasourceCount == 0 {
 		log.Fatalf("No datasources configured")
 	}
-	var err error
 	timezone, err = time.LoadLocation(flagTz)
 	if err != nil {
 		log.Fatalf("failed to load timezone 1: %v", err)
@@ -88,6 +199,7 @@ func main() {
 	var refreshUi = true  // When set this will cause a UI refresh/clear
 	var syncPromCount = 0 // This provides a counter to use for looping through all the datasource polls
 	loadConfig()          // Loads flags and configs
+	loadPromCache()       // Loads last-known samples, resilient to a missing or corrupt cache file
 
 	if err := ui.Init(); err != nil {
 		log.Fatalf("failed to initialize termui: %v", err)
@@ -108,9 +220,50 @@ func main() {
 	chart0 := widgets.NewPlot()
 	chart0.Border = false
 
+	// Optional grid-mode panels, only built when dashclock.json declares a "layout"
+	var grid *ui.Grid
+	var gauges []*widgets.Gauge
+	var logList *widgets.List
+	var sysInfo *widgets.Paragraph
+	if dsLayout != nil {
+		panels := map[string]ui.Drawable{
+			"clock":  pClock,
+			"date":   pDate,
+			"chart":  chart0,
+			"metric": pMetric,
+		}
+		if layoutHasPanel(dsLayout, "gauges") {
+			gauges = make([]*widgets.Gauge, datasourceCount)
+			for i, ds := range dsConfig {
+				g := widgets.NewGauge()
+				g.Title = ds.Title
+				gauges[i] = g
+			}
+		}
+		if layoutHasPanel(dsLayout, "log") {
+			logList = widgets.NewList()
+			logList.Title = "Log"
+			log.SetOutput(&logTailWriter{}) // Only capture into the log panel once something renders it
+		}
+		if layoutHasPanel(dsLayout, "sysinfo") {
+			sysInfo = widgets.NewParagraph()
+			sysInfo.Title = "System Info"
+		}
+		grid = buildGrid(dsLayout, panels, gauges, logList, sysInfo)
+	}
+	// Grid widgets (gauges in particular) are sized and titled for the startup datasource list and
+	// are never rebuilt; a reload that changes the datasource count would leave stale/missing gauges.
+	gridDatasourceCount := datasourceCount
+
 	resizeUi := func() { // This is to resize the window. Executed on start and resize
+		uiMutex.Lock()
+		defer uiMutex.Unlock()
 		width, height = ui.TerminalDimensions()
-		if width == 240 && height == 30 { // This for the 8" LCD
+		if grid != nil {
+			grid.SetRect(0, 0, width, height)
+			fontClock, fontDate, fontLabel, fontClockWidth, fontDateWidth = gridClockFont(dsLayout, width, height)
+			ui.Clear()
+		} else if width == 240 && height == 30 { // This for the 8" LCD
 			pClock.SetRect(100, -3, width-28, 17)
 			pDate.SetRect(width-30, -1, width+1, 17)
 			chart0.SetRect(0, 16, width+1, height+1)
@@ -164,9 +317,11 @@ func main() {
 	}
 
 	syncProm := func() { // This loads data from prometheus and draws the graph
+		uiMutex.Lock()
+		defer uiMutex.Unlock()
 		fontColor := "white"                 // Default color
 		i := syncPromCount % datasourceCount // Index for the datasource config
-		r0, l0 := prometheusQueryRange(dsConfig[i].Prom, dsConfig[i].Query, width-8, 60, nullValue, timezone)
+		r0, l0, cachedAt := prometheusQueryRange(dsConfig[i], width-8, 60, nullValue, timezone)
 		if len(r0) > 0 {
 			chart0.Data = [][]float64{r0}
 			chart0.DataLabels = l0
@@ -189,33 +344,54 @@ func main() {
 				} else if r0[len(r0)-1] > dsConfig[i].Warn {
 					fontColor = "yellow"
 				}
+				if cachedAt.IsZero() { // Don't alert off stale, cached data
+					evaluateAlert(dsConfig[i], r0[len(r0)-1])
+				}
+			}
+			staleSuffix := ""
+			if !cachedAt.IsZero() {
+				fontColor = "clear"
+				staleSuffix = fmt.Sprintf(" (stale, %dm ago)", int(time.Since(cachedAt).Minutes()))
 			}
 			if len(fontLabel) > 0 {
 				myFigLabel := figure.NewFigure(dsConfig[i].Title, fontLabel, false)
 				myFigValue := figure.NewFigure(fmt.Sprintf("%01.0f%s", r0[len(r0)-1], dsConfig[i].Unit), fontLabel, false)
-				pMetric.Text = fmt.Sprintf("[%s](fg:white)\n[%s](fg:%s)", strings.Join(myFigLabel.Slicify(), "\n"), strings.Join(myFigValue.Slicify(), "\n"), fontColor)
-				ui.Render(pMetric)
+				pMetric.Text = fmt.Sprintf("[%s](fg:white)\n[%s](fg:%s)%s", strings.Join(myFigLabel.Slicify(), "\n"), strings.Join(myFigValue.Slicify(), "\n"), fontColor, staleSuffix)
+				if grid == nil {
+					ui.Render(pMetric)
+				}
 			} else {
-				chart0.Title = fmt.Sprintf("%s %01.0f%s", dsConfig[i].Title, r0[len(r0)-1], dsConfig[i].Unit)
+				chart0.Title = fmt.Sprintf("%s %01.0f%s%s", dsConfig[i].Title, r0[len(r0)-1], dsConfig[i].Unit, staleSuffix)
+			}
+			if i < len(gauges) {
+				updateGauge(gauges[i], dsConfig[i], r0[len(r0)-1])
+			}
+			if grid == nil {
+				ui.Render(chart0)
 			}
-			ui.Render(chart0)
 		} else {
 			if len(fontLabel) > 0 {
 				fontColor = "red"
 				myFigLabel := figure.NewFigure(dsConfig[i].Title, fontLabel, false)
 				myFigValue := figure.NewFigure("PROM ERROR", fontLabel, false)
 				pMetric.Text = fmt.Sprintf("[%s](fg:red)\n[%s](fg:%s)", strings.Join(myFigLabel.Slicify(), "\n"), strings.Join(myFigValue.Slicify(), "\n"), fontColor)
-				ui.Render(pMetric)
+				if grid == nil {
+					ui.Render(pMetric)
+				}
 			} else {
 				chart0.Title = fmt.Sprintf("%s PROM ERROR %s", dsConfig[i].Title, dsConfig[i].Prom)
 			}
-			ui.Render(chart0)
+			if grid == nil {
+				ui.Render(chart0)
+			}
 		}
 		syncPromCount++
 
 	}
 
 	syncClock := func() { // This draws the clock
+		uiMutex.Lock()
+		defer uiMutex.Unlock()
 		timeNow := time.Now().In(timezone).Format("1504")
 		dateNow := time.Now().In(timezone).Format("01")
 		monthNow := time.Now().In(timezone).Format("Jan")
@@ -242,7 +418,9 @@ func main() {
 			strings.Join(rightAlignText(myFigDay.Slicify(), fontDateWidth), "\n"),
 			strings.Join(rightAlignText(myFigDate.Slicify(), fontDateWidth), "\n"),
 			strings.Join(rightAlignText(myFigMonth.Slicify(), fontDateWidth), "\n")) // We right align this so the date is not so close to the clock
-		ui.Render(pClock, pDate)
+		if grid == nil {
+			ui.Render(pClock, pDate)
+		}
 	}
 
 	syncTerminal := func() {
@@ -250,9 +428,20 @@ func main() {
 			resizeUi()
 			refreshUi = false
 		}
-		if len(fontClock) > 0 { // 0 means display not good enough
+		if len(fontClock) > 0 { // 0 means clock display not good enough; metrics still need to poll
 			syncClock()
-			syncProm()
+		}
+		syncProm()
+		if grid != nil {
+			uiMutex.Lock()
+			if sysInfo != nil {
+				sysInfo.Text = sysInfoText()
+			}
+			if logList != nil {
+				logList.Rows = logTailSnapshot()
+			}
+			ui.Render(grid)
+			uiMutex.Unlock()
 		}
 	}
 
@@ -260,6 +449,104 @@ func main() {
 		refreshUi = true
 	}
 
+	// reloadConfig re-parses flagFile, validates it, and only then swaps in the new datasources/
+	// timezone/refresh cadence. On failure it keeps the live config and surfaces the error in pMetric.
+	// reloadMu serializes calls: SIGHUP and the file-watcher can both fire for the same edit.
+	var reloadMu sync.Mutex
+	reloadConfig := func() {
+		reloadMu.Lock()
+		defer reloadMu.Unlock()
+		cfg, err := parseConfigFile(flagFile)
+		if err == nil {
+			err = validateConfig(cfg)
+		}
+		if err == nil && grid != nil && len(cfg.Datasources) != gridDatasourceCount {
+			err = fmt.Errorf("grid layout was built for %d datasource(s); reload with a different datasource count is not supported, restart instead", gridDatasourceCount)
+		}
+		if err != nil {
+			log.Printf("Config reload failed, keeping existing config: %v\n", err)
+			uiMutex.Lock()
+			pMetric.Text = fmt.Sprintf("[Config reload failed: %v](fg:red)", err)
+			if grid != nil {
+				ui.Render(grid)
+			} else {
+				ui.Render(pMetric)
+			}
+			uiMutex.Unlock()
+			return
+		}
+
+		newTz := flagTz
+		if cfg.Timezone != "" {
+			newTz = cfg.Timezone
+		}
+		loc, _ := time.LoadLocation(newTz) // Already validated above
+		newRefresh := flagRefresh
+		if cfg.Refresh > 0 {
+			newRefresh = cfg.Refresh
+		}
+
+		uiMutex.Lock()
+		dsConfig = cfg.Datasources
+		datasourceCount = len(cfg.Datasources)
+		timezone = loc
+		uiMutex.Unlock()
+		flagTz = newTz
+
+		if newRefresh != flagRefresh {
+			flagRefresh = newRefresh
+			cron.RemoveByTag("sync")
+			cron.Every(flagRefresh).Second().SingletonMode().Tag("sync").Do(syncTerminal)
+		}
+
+		log.Printf("Reloaded config from %s\n", flagFile)
+		markRefresh()
+		cron.RunByTag("sync")
+	}
+
+	sighupCh := make(chan os.Signal, 1)
+	signal.Notify(sighupCh, syscall.SIGHUP)
+	go func() {
+		for range sighupCh {
+			reloadConfig()
+		}
+	}()
+
+	// Watch flagFile's parent directory rather than the file itself: editors and config-management
+	// tools commonly replace a file via write-to-temp + rename, which orphans a single-file watch
+	// (the inode fsnotify was watching is gone) with no further events ever firing.
+	if watcher, err := fsnotify.NewWatcher(); err != nil {
+		log.Printf("Could not create config file watcher: %v\n", err)
+	} else {
+		defer watcher.Close()
+		configDir := filepath.Dir(flagFile)
+		configName := filepath.Base(flagFile)
+		if err := watcher.Add(configDir); err != nil {
+			log.Printf("Could not watch %s for changes: %v\n", configDir, err)
+		} else {
+			go func() {
+				for {
+					select {
+					case event, ok := <-watcher.Events:
+						if !ok {
+							return
+						}
+						if filepath.Base(event.Name) != configName {
+							continue
+						}
+						if event.Op&(fsnotify.Write|fsnotify.Create) != 0 {
+							reloadConfig()
+						}
+					case _, ok := <-watcher.Errors:
+						if !ok {
+							return
+						}
+					}
+				}
+			}()
+		}
+	}
+
 	cron.Every(1).Hour().SingletonMode().Tag("hourly").Do(markRefresh)
 	cron.Every(flagRefresh).Second().SingletonMode().Tag("sync").Do(syncTerminal)
 	cron.StartAsync()
@@ -314,20 +601,400 @@ func rightAlignText(text []string, width int) []string { // Right aligns a strin
 	return text
 }
 
-func prometheusQueryRange(promFlag string, query string, length int, intervalSeconds int, nilValue float64, tz *time.Location) ([]float64, []string) {
-	var values []float64
-	var labels []string
+// layoutHasPanel reports whether name appears anywhere in layout's rows.
+func layoutHasPanel(layout *LayoutConfig, name string) bool {
+	for _, row := range layout.Rows {
+		for _, p := range row.Panels {
+			if p.Panel == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// buildGrid turns a LayoutConfig into a ui.Grid, wiring each row's named panels to their widgets.
+// The "gauges" panel is special-cased: it expands to one equal-width column per datasource.
+func buildGrid(layout *LayoutConfig, panels map[string]ui.Drawable, gauges []*widgets.Gauge, logList *widgets.List, sysInfo *widgets.Paragraph) *ui.Grid {
+	if logList != nil {
+		panels["log"] = logList
+	}
+	if sysInfo != nil {
+		panels["sysinfo"] = sysInfo
+	}
+
+	var rowItems []interface{}
+	for _, row := range layout.Rows {
+		var colItems []interface{}
+		for _, p := range row.Panels {
+			if p.Panel == "gauges" {
+				colItems = append(colItems, ui.NewCol(p.Width, ui.NewRow(1.0, gaugeCols(gauges)...)))
+				continue
+			}
+			widget, ok := panels[p.Panel]
+			if !ok {
+				log.Printf("Unknown layout panel %q, skipping\n", p.Panel)
+				continue
+			}
+			colItems = append(colItems, ui.NewCol(p.Width, widget))
+		}
+		if len(colItems) > 0 {
+			rowItems = append(rowItems, ui.NewRow(row.Height, colItems...))
+		}
+	}
+
+	grid := ui.NewGrid()
+	grid.Set(rowItems...)
+	return grid
+}
+
+// gaugeCols lays out gauges as equal-width columns within their row.
+func gaugeCols(gauges []*widgets.Gauge) []interface{} {
+	if len(gauges) == 0 {
+		return nil
+	}
+	colWidth := 1.0 / float64(len(gauges))
+	cols := make([]interface{}, len(gauges))
+	for i, g := range gauges {
+		cols[i] = ui.NewCol(colWidth, g)
+	}
+	return cols
+}
+
+// updateGauge sets a datasource's Gauge percent/color from its latest value and Warn/Error thresholds.
+func updateGauge(g *widgets.Gauge, ds Datasource, value float64) {
+	percent := int(value)
+	if percent < 0 {
+		percent = 0
+	} else if percent > 100 {
+		percent = 100
+	}
+	g.Percent = percent
+	g.Label = fmt.Sprintf("%01.0f%s", value, ds.Unit)
+	g.BarColor = ui.ColorGreen
+	if ds.Warn > 0 {
+		if value > ds.Error {
+			g.BarColor = ui.ColorRed
+		} else if value > ds.Warn {
+			g.BarColor = ui.ColorYellow
+		}
+	}
+}
+
+// gridClockFont picks the clock/date figlet fonts and right-align widths for grid mode, using the
+// same size thresholds as the built-in ladder but applied to the "clock" panel's own cell, not the
+// whole terminal.
+func gridClockFont(layout *LayoutConfig, termWidth int, termHeight int) (clockFont string, dateFont string, label string, clockWidth int, dateWidth int) {
+	for _, row := range layout.Rows {
+		for _, p := range row.Panels {
+			if p.Panel != "clock" {
+				continue
+			}
+			w := int(float64(termWidth) * p.Width)
+			h := int(float64(termHeight) * row.Height)
+			switch {
+			case w >= 131 && h >= 17:
+				return "doh", "standard", "", w - 28, 29
+			case w >= 68 && h >= 11:
+				return "colossal", "mini", "", w - 16, 17
+			case w >= 40 && h >= 6:
+				return "standard", "term", "", w - 6, 4
+			default:
+				return "", "", "", 0, 0
+			}
+		}
+	}
+	return "", "", "", 0, 0
+}
+
+// logTailMax bounds how many lines the log panel keeps in memory.
+const logTailMax = 200
+
+var logTailRows []string
+var logTailMu sync.Mutex
+
+// logTailWriter is an io.Writer set via log.SetOutput so log.Printf output can be tailed in the log panel.
+type logTailWriter struct{}
+
+func (w *logTailWriter) Write(p []byte) (int, error) {
+	line := strings.TrimRight(string(p), "\n")
+	logTailMu.Lock()
+	logTailRows = append(logTailRows, line)
+	if len(logTailRows) > logTailMax {
+		logTailRows = logTailRows[len(logTailRows)-logTailMax:]
+	}
+	logTailMu.Unlock()
+	return len(p), nil
+}
+
+// logTailSnapshot returns a copy of the captured log lines for rendering into the log panel.
+func logTailSnapshot() []string {
+	logTailMu.Lock()
+	defer logTailMu.Unlock()
+	return append([]string(nil), logTailRows...)
+}
+
+var startTime = time.Now()
+
+// sysInfoText renders the "system info" panel: hostname, OS/arch, uptime, and IPv4/IPv6 status.
+func sysInfoText() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+	ipv4, ipv6 := false, false
+	if addrs, err := net.InterfaceAddrs(); err == nil {
+		for _, addr := range addrs {
+			ipNet, ok := addr.(*net.IPNet)
+			if !ok || ipNet.IP.IsLoopback() {
+				continue
+			}
+			if ipNet.IP.To4() != nil {
+				ipv4 = true
+			} else {
+				ipv6 = true
+			}
+		}
+	}
+	return fmt.Sprintf("Host: %s\nOS/Arch: %s/%s\nUptime: %s\nIPv4: %s\nIPv6: %s",
+		hostname, runtime.GOOS, runtime.GOARCH, time.Since(startTime).Round(time.Second), upDown(ipv4), upDown(ipv6))
+}
+
+func upDown(up bool) string {
+	if up {
+		return "up"
+	}
+	return "down"
+}
+
+// promClientCache holds one v1.API per unique endpoint+auth combination, so a fresh client/RoundTripper
+// isn't constructed on every scrape (the previous behaviour built one every flagRefresh seconds).
+var promClientCache = make(map[string]v1.API)
+var promClientCacheMu sync.Mutex
+
+// promClientCacheKey identifies a Datasource's endpoint+auth+transport combination for promClientCache.
+func promClientCacheKey(ds Datasource) string {
+	return strings.Join([]string{
+		ds.Prom, ds.BasicAuthUser, ds.BasicAuthPass, ds.BearerToken, ds.BearerTokenFile,
+		strings.Join(ds.Headers, "\x00"), ds.CAFile, ds.CertFile, ds.KeyFile,
+		strconv.FormatBool(ds.InsecureSkipVerify),
+		strconv.Itoa(ds.DialTimeout), strconv.Itoa(ds.MaxIdleConnsPerHost),
+	}, "\x00")
+}
+
+// headerRoundTripper injects basic auth, a bearer token, and arbitrary static headers into every
+// outbound request before delegating to the underlying transport. bearerTokenFile, if set, is
+// re-read on every request rather than cached, so a rotated/projected token (e.g. a Kubernetes
+// service-account token) is picked up without needing to evict the cached client.
+type headerRoundTripper struct {
+	next            http.RoundTripper
+	basicUser       string
+	basicPass       string
+	bearerToken     string
+	bearerTokenFile string
+	headers         []string
+}
+
+func (h *headerRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	if h.basicUser != "" || h.basicPass != "" {
+		req.SetBasicAuth(h.basicUser, h.basicPass)
+	}
+	bearerToken := h.bearerToken
+	if h.bearerTokenFile != "" {
+		if b, err := os.ReadFile(h.bearerTokenFile); err != nil {
+			log.Printf("Error reading bearerTokenFile %s: %v\n", h.bearerTokenFile, err)
+		} else {
+			bearerToken = strings.TrimSpace(string(b))
+		}
+	}
+	if bearerToken != "" {
+		req.Header.Set("Authorization", "Bearer "+bearerToken)
+	}
+	for _, header := range h.headers {
+		name, value, ok := strings.Cut(header, ":")
+		if !ok {
+			continue
+		}
+		req.Header.Set(strings.TrimSpace(name), strings.TrimSpace(value))
+	}
+	return h.next.RoundTrip(req)
+}
+
+// newPromRoundTripper builds the http.RoundTripper for a Datasource, applying its TLS and auth options.
+func newPromRoundTripper(ds Datasource) (http.RoundTripper, error) {
+	tlsConfig := &tls.Config{InsecureSkipVerify: ds.InsecureSkipVerify}
+	if ds.CAFile != "" {
+		caCert, err := os.ReadFile(ds.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read caFile: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caCert) {
+			return nil, fmt.Errorf("failed to parse caFile %s", ds.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if ds.CertFile != "" && ds.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(ds.CertFile, ds.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load certFile/keyFile: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	dialTimeout := 5 * time.Second
+	if ds.DialTimeout > 0 {
+		dialTimeout = time.Duration(ds.DialTimeout) * time.Second
+	}
+	maxIdleConnsPerHost := http.DefaultMaxIdleConnsPerHost
+	if ds.MaxIdleConnsPerHost > 0 {
+		maxIdleConnsPerHost = ds.MaxIdleConnsPerHost
+	}
+
+	if ds.BearerTokenFile != "" { // Fail fast if it's missing/unreadable; RoundTrip re-reads it per-request
+		if _, err := os.ReadFile(ds.BearerTokenFile); err != nil {
+			return nil, fmt.Errorf("failed to read bearerTokenFile: %w", err)
+		}
+	}
+
+	return &headerRoundTripper{
+		next: &http.Transport{
+			TLSClientConfig:     tlsConfig,
+			DialContext:         (&net.Dialer{Timeout: dialTimeout}).DialContext,
+			MaxIdleConnsPerHost: maxIdleConnsPerHost,
+		},
+		basicUser:       ds.BasicAuthUser,
+		basicPass:       ds.BasicAuthPass,
+		bearerToken:     ds.BearerToken,
+		bearerTokenFile: ds.BearerTokenFile,
+		headers:         ds.Headers,
+	}, nil
+}
+
+// getPromAPI returns the cached v1.API for ds's endpoint+auth combination, building and caching one on first use.
+func getPromAPI(ds Datasource) (v1.API, error) {
+	key := promClientCacheKey(ds)
+
+	promClientCacheMu.Lock()
+	defer promClientCacheMu.Unlock()
+
+	if v1api, ok := promClientCache[key]; ok {
+		return v1api, nil
+	}
+
+	roundTripper, err := newPromRoundTripper(ds)
+	if err != nil {
+		return nil, err
+	}
 	client, err := api.NewClient(api.Config{
-		Address: promFlag,
+		Address:      ds.Prom,
+		RoundTripper: roundTripper,
 	})
 	if err != nil {
-		ui.Close()
-		log.Printf("Error creating client: %v\n", err)
-		os.Exit(1)
+		return nil, err
 	}
 
 	v1api := v1.NewAPI(client)
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	promClientCache[key] = v1api
+	return v1api, nil
+}
+
+// promCacheEntry is one datasource's last-known sample, persisted to flagCache for offline resilience.
+type promCacheEntry struct {
+	Values []float64 `json:"values"`
+	Labels []string  `json:"labels"`
+	Time   time.Time `json:"time"`
+}
+
+var promCache = make(map[string]promCacheEntry)
+var promCacheMu sync.Mutex
+
+// loadPromCache loads flagCache into promCache at startup. A missing or corrupt file just leaves
+// promCache empty, so a fresh install still starts up fine.
+func loadPromCache() {
+	byteValue, err := os.ReadFile(flagCache)
+	if err != nil {
+		return
+	}
+	var entries map[string]promCacheEntry
+	if err := json.Unmarshal(byteValue, &entries); err != nil {
+		log.Printf("Ignoring corrupt cache file %s: %v\n", flagCache, err)
+		return
+	}
+
+	promCacheMu.Lock()
+	promCache = entries
+	promCacheMu.Unlock()
+}
+
+// loadPromCacheEntry returns the last-known series for cacheKey, or nil/zero-time if there is none cached.
+func loadPromCacheEntry(cacheKey string) ([]float64, []string, time.Time) {
+	promCacheMu.Lock()
+	defer promCacheMu.Unlock()
+
+	entry, ok := promCache[cacheKey]
+	if !ok {
+		return nil, nil, time.Time{}
+	}
+	return entry.Values, entry.Labels, entry.Time
+}
+
+// savePromCacheEntry records a successful query result for cacheKey and atomically rewrites flagCache
+// (write to a temp file, then rename over the target, so a crash mid-write can't corrupt the cache).
+func savePromCacheEntry(cacheKey string, values []float64, labels []string) {
+	promCacheMu.Lock()
+	promCache[cacheKey] = promCacheEntry{Values: values, Labels: labels, Time: time.Now()}
+	byteValue, err := json.Marshal(promCache)
+	promCacheMu.Unlock()
+	if err != nil {
+		log.Printf("Error marshalling cache: %v\n", err)
+		return
+	}
+
+	tmpFile := flagCache + ".tmp"
+	if err := os.WriteFile(tmpFile, byteValue, 0644); err != nil {
+		log.Printf("Error writing cache file %s: %v\n", tmpFile, err)
+		return
+	}
+	if err := os.Rename(tmpFile, flagCache); err != nil {
+		log.Printf("Error renaming cache file %s: %v\n", tmpFile, err)
+	}
+}
+
+// prometheusQueryRange returns the series for ds, plus a zero time.Time if the data is fresh, or the
+// time it was originally fetched if it's being served from promCache because of a query failure.
+// prometheusQueryRange dispatches to the query method matching ds.Type: a range query returning a
+// matrix (the default), or an instant query returning a vector/scalar synthesized into a flat series.
+func prometheusQueryRange(ds Datasource, length int, intervalSeconds int, nilValue float64, tz *time.Location) ([]float64, []string, time.Time) {
+	switch ds.Type {
+	case "instant", "scalar":
+		return prometheusQueryInstant(ds, length, intervalSeconds, tz)
+	default:
+		return prometheusQueryMatrix(ds, length, intervalSeconds, nilValue, tz)
+	}
+}
+
+// prometheusQueryMatrix runs ds.Query as a range query. Any error, or a result that isn't a matrix
+// with data, degrades to the last-known cached series rather than exiting.
+func prometheusQueryMatrix(ds Datasource, length int, intervalSeconds int, nilValue float64, tz *time.Location) ([]float64, []string, time.Time) {
+	var values []float64
+	var labels []string
+	cacheKey := datasourceKey(ds)
+
+	v1api, err := getPromAPI(ds)
+	if err != nil {
+		log.Printf("Error creating client: %v\n", err)
+		return loadPromCacheEntry(cacheKey)
+	}
+
+	queryTimeout := 5 * time.Second
+	if ds.Timeout > 0 {
+		queryTimeout = time.Duration(ds.Timeout) * time.Second
+	}
+	// The client-side deadline must cover at least ds.Timeout, which v1.WithTimeout only enforces
+	// server-side; a couple of seconds of slack lets a server-side timeout error make it back to us.
+	ctx, cancel := context.WithTimeout(context.Background(), queryTimeout+2*time.Second)
 	defer cancel()
 
 	timeNow := time.Now().Round(time.Second) // We want a nice round number
@@ -338,21 +1005,77 @@ func prometheusQueryRange(promFlag string, query string, length int, intervalSec
 		Step:  time.Duration(intervalSeconds) * time.Second, // Data will be per minute
 	}
 
-	result, _, err := v1api.QueryRange(ctx, query, r, v1.WithTimeout(5*time.Second))
+	result, _, err := v1api.QueryRange(ctx, ds.Query, r, v1.WithTimeout(queryTimeout))
 	if err != nil {
-		return values, labels
+		return loadPromCacheEntry(cacheKey)
 	}
-	switch result.Type() {
-	case model.ValMatrix:
+	matrix, ok := result.(model.Matrix)
+	if !ok || len(matrix) == 0 {
+		log.Printf("Query %q for %q did not return a populated matrix, got %#v\n", ds.Query, ds.Title, result)
+		return loadPromCacheEntry(cacheKey)
+	}
+	for i := length; i >= 0; i-- { // Flipped since we cant drawRight
+		timeThen := timeNow.Add(-time.Second * time.Duration(i*intervalSeconds))
+		values = append(values, findPromValue(*matrix[0], timeThen, nilValue))
+		labels = append(labels, timeThen.In(tz).Format("15:04"))
+	}
+	if len(values) > 0 {
+		savePromCacheEntry(cacheKey, values, labels)
+	}
+	return values, labels, time.Time{}
+}
+
+// prometheusQueryInstant runs ds.Query as an instant query (Type "instant" or "scalar"), taking the
+// first vector sample or the scalar value and synthesizing a flat series so the chart/gauge/alerting
+// machinery, which all expect a history, still has something to draw.
+func prometheusQueryInstant(ds Datasource, length int, intervalSeconds int, tz *time.Location) ([]float64, []string, time.Time) {
+	var values []float64
+	var labels []string
+	cacheKey := datasourceKey(ds)
+
+	v1api, err := getPromAPI(ds)
+	if err != nil {
+		log.Printf("Error creating client: %v\n", err)
+		return loadPromCacheEntry(cacheKey)
+	}
+
+	queryTimeout := 5 * time.Second
+	if ds.Timeout > 0 {
+		queryTimeout = time.Duration(ds.Timeout) * time.Second
+	}
+	// The client-side deadline must cover at least ds.Timeout, which v1.WithTimeout only enforces
+	// server-side; a couple of seconds of slack lets a server-side timeout error make it back to us.
+	ctx, cancel := context.WithTimeout(context.Background(), queryTimeout+2*time.Second)
+	defer cancel()
+
+	result, _, err := v1api.Query(ctx, ds.Query, time.Now(), v1.WithTimeout(queryTimeout))
+	if err != nil {
+		return loadPromCacheEntry(cacheKey)
+	}
+
+	var value float64
+	switch v := result.(type) {
+	case model.Vector:
+		if len(v) == 0 {
+			log.Printf("Query %q for %q returned an empty vector\n", ds.Query, ds.Title)
+			return loadPromCacheEntry(cacheKey)
+		}
+		value = float64(v[0].Value)
+	case *model.Scalar:
+		value = float64(v.Value)
 	default:
-		log.Fatalf("Only support model.Matrix return, got %#v", result)
+		log.Printf("Query %q for %q returned unsupported type %T\n", ds.Query, ds.Title, result)
+		return loadPromCacheEntry(cacheKey)
 	}
+
+	timeNow := time.Now().Round(time.Second)
 	for i := length; i >= 0; i-- { // Flipped since we cant drawRight
 		timeThen := timeNow.Add(-time.Second * time.Duration(i*intervalSeconds))
-		values = append(values, findPromValue(*result.(model.Matrix)[0], timeThen, nilValue))
+		values = append(values, value)
 		labels = append(labels, timeThen.In(tz).Format("15:04"))
 	}
-	return values, labels
+	savePromCacheEntry(cacheKey, values, labels)
+	return values, labels, time.Time{}
 }
 
 func findPromValue(sampleSet model.SampleStream, timestamp time.Time, nilValue float64) float64 { // This avoids having to do a lookup table
@@ -363,3 +1086,119 @@ func findPromValue(sampleSet model.SampleStream, timestamp time.Time, nilValue f
 	}
 	return nilValue
 }
+
+// alertState tracks the last severity fired for a datasource, so a metric that stays red doesn't spam the webhook.
+type alertState struct {
+	severity string // "", "warn" or "error"
+	lastSent time.Time
+}
+
+var alertStates = make(map[string]*alertState)
+var alertStatesMu sync.Mutex
+
+// alertPayload is the JSON body POSTed to a datasource's alert webhook.
+type alertPayload struct {
+	Title     string  `json:"title"`
+	Query     string  `json:"query"`
+	Value     float64 `json:"value"`
+	Threshold float64 `json:"threshold"`
+	Severity  string  `json:"severity"` // "warn", "error" or "resolved"
+}
+
+// datasourceKey identifies a Datasource by title+query, used to key both the alert state map and the on-disk sample cache.
+func datasourceKey(ds Datasource) string {
+	return ds.Title + "\x00" + ds.Query
+}
+
+// evaluateAlert checks value against ds's Warn/Error thresholds and fires (or resolves) a webhook alert
+// on state transitions, debounced by -alert-resend while the state is unchanged.
+func evaluateAlert(ds Datasource, value float64) {
+	if ds.Warn <= 0 { // No thresholds configured, nothing to alert on
+		return
+	}
+	webhook := ds.Webhook
+	if webhook == "" {
+		webhook = flagWebhook
+	}
+	if webhook == "" {
+		return
+	}
+
+	severity := ""
+	threshold := ds.Warn
+	if value > ds.Warn {
+		severity = "warn"
+	}
+	if ds.Error > 0 && value > ds.Error { // Error == 0 means no error threshold configured, cap at warn
+		severity = "error"
+		threshold = ds.Error
+	}
+
+	resendInterval := time.Duration(flagAlertResend) * time.Minute
+	key := datasourceKey(ds)
+
+	alertStatesMu.Lock()
+	state, ok := alertStates[key]
+	if !ok {
+		state = &alertState{}
+		alertStates[key] = state
+	}
+	fire := false
+	if severity == "" {
+		fire = state.severity != "" // Was alerting, now resolved
+	} else {
+		fire = severity != state.severity || time.Since(state.lastSent) >= resendInterval
+	}
+	if fire {
+		state.lastSent = time.Now()
+	}
+	state.severity = severity
+	alertStatesMu.Unlock()
+
+	if !fire {
+		return
+	}
+
+	payload := alertPayload{Title: ds.Title, Query: ds.Query, Value: value, Threshold: threshold, Severity: severity}
+	if severity == "" {
+		payload.Severity = "resolved"
+		payload.Threshold = ds.Warn
+	}
+
+	timeout := 5 * time.Second
+	if ds.WebhookTimeout > 0 {
+		timeout = time.Duration(ds.WebhookTimeout) * time.Second
+	}
+	go postWebhook(webhook, timeout, flagWebhookRetry, payload)
+}
+
+// postWebhook POSTs payload as JSON to url, retrying up to retries times on error or a non-2xx response.
+func postWebhook(url string, timeout time.Duration, retries int, payload alertPayload) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		log.Printf("Error marshalling alert payload: %v\n", err)
+		return
+	}
+
+	client := &http.Client{Timeout: timeout}
+	var lastErr error
+	for attempt := 0; attempt <= retries; attempt++ {
+		req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+		if err != nil {
+			lastErr = err
+			break
+		}
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := client.Do(req)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		resp.Body.Close()
+		if resp.StatusCode < 300 {
+			return
+		}
+		lastErr = fmt.Errorf("webhook returned status %s", resp.Status)
+	}
+	log.Printf("Error posting alert webhook to %s: %v\n", url, lastErr)
+}